@@ -15,14 +15,39 @@
 package logstream
 
 import (
+	"cmp"
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
+// MTimePriority is the reserved Priority token that, when present (optionally
+// prefixed with "^"), causes ByPriority.Less to compare Logfile.ModTime
+// instead of a named match part. This lets callers order purely by
+// filesystem mtime without encoding a date into the FileMatch regexp.
+const MTimePriority = "MTime"
+
+// RulePriority is the reserved Priority token that, when present (optionally
+// prefixed with "^"), causes ByPriority.Less to compare the summed score
+// produced by SortPattern.PriorityRules instead of a named match part.
+const RulePriority = "Priority"
+
+// PriorityRule boosts or deprioritizes a logfile's sort score based on a
+// regular expression matched against its FileName, independent of the
+// named captures used for FileMatch. Every rule whose Pattern matches
+// contributes its Priority to the logfile's summed score.
+type PriorityRule struct {
+	Pattern  *regexp.Regexp
+	Priority int
+}
+
 var MonthLookup = map[string]int{
 	"january":   1,
 	"jan":       1,
@@ -68,6 +93,74 @@ var DayLookup = map[string]int{
 
 var digitRegex = regexp.MustCompile(`^\d+$`)
 
+// Translator converts a raw filename submatch into an integer sort value.
+// Registering one under a capture name lets PopulateMatchParts handle
+// rotation schemes that aren't plain digits or English month/day names,
+// e.g. RFC3339 timestamps, epoch seconds, hex sequence numbers, quarter
+// names, or a syslog priority.
+type Translator func(raw string) (int, error)
+
+// DefaultTranslators are registered for every SortPattern unless a
+// SortPattern.Translators entry of the same name overrides them.
+var DefaultTranslators = map[string]Translator{
+	"MonthName": translateMonthName,
+	"DayName":   translateDayName,
+	"RFC3339":   translateRFC3339,
+	"Unix":      translateUnix,
+	"Hex":       translateHex,
+}
+
+func translateMonthName(raw string) (int, error) {
+	if score, ok := MonthLookup[strings.ToLower(raw)]; ok {
+		return score, nil
+	}
+	return 0, errors.New("Unable to locate month name: " + raw)
+}
+
+func translateDayName(raw string) (int, error) {
+	if score, ok := DayLookup[strings.ToLower(raw)]; ok {
+		return score, nil
+	}
+	return 0, errors.New("Unable to locate day name : " + raw)
+}
+
+func translateRFC3339(raw string) (int, error) {
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return 0, errors.New("Unable to parse RFC3339 timestamp: " + raw)
+	}
+	return int(t.Unix()), nil
+}
+
+func translateUnix(raw string) (int, error) {
+	score, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, errors.New("Unable to parse unix timestamp: " + raw)
+	}
+	return score, nil
+}
+
+func translateHex(raw string) (int, error) {
+	score, err := strconv.ParseInt(raw, 16, 64)
+	if err != nil {
+		return 0, errors.New("Unable to parse hex value: " + raw)
+	}
+	return int(score), nil
+}
+
+// mergeTranslators layers custom on top of DefaultTranslators, letting callers
+// override a built-in translator by registering one under the same name.
+func mergeTranslators(custom map[string]Translator) map[string]Translator {
+	merged := make(map[string]Translator, len(DefaultTranslators)+len(custom))
+	for name, t := range DefaultTranslators {
+		merged[name] = t
+	}
+	for name, t := range custom {
+		merged[name] = t
+	}
+	return merged
+}
+
 // Custom multiple error type that satisfies Go error interface but has
 // alternate printing options
 type MultipleError []string
@@ -95,11 +188,32 @@ type Logfile struct {
 	MatchParts map[string]int
 	// The raw string matches from the filename
 	StringMatchParts map[string]string
+	// The filesystem modification time of the file, populated by
+	// ScanDirectoryForLogfiles. Used for sorting when MTimePriority appears
+	// in a SortPattern's Priority list.
+	ModTime time.Time
+	// The summed Priority of every SortPattern.PriorityRule whose Pattern
+	// matches FileName. Used for sorting when RulePriority appears in a
+	// SortPattern's Priority list.
+	Priority int
 }
 
-func (l *Logfile) PopulateMatchParts(subexpNames, matches []string, translation SubmatchTranslationMap) error {
+// ApplyPriorityRules sums the Priority of every rule whose Pattern matches
+// FileName into l.Priority.
+func (l *Logfile) ApplyPriorityRules(rules []PriorityRule) {
+	l.Priority = 0
+	for _, rule := range rules {
+		if rule.Pattern.MatchString(l.FileName) {
+			l.Priority += rule.Priority
+		}
+	}
+}
+
+func (l *Logfile) PopulateMatchParts(subexpNames, matches []string, translation SubmatchTranslationMap,
+	translators map[string]Translator) error {
+
 	var score int
-	var ok bool
+	var err error
 	if l.MatchParts == nil {
 		l.MatchParts = make(map[string]int)
 	}
@@ -111,18 +225,13 @@ func (l *Logfile) PopulateMatchParts(subexpNames, matches []string, translation
 		// Store the raw string
 		l.StringMatchParts[name] = matchValue
 
-		lowerValue := strings.ToLower(matchValue)
 		score = -1
 		if name == "" {
 			continue
 		}
-		if name == "MonthName" {
-			if score, ok = MonthLookup[lowerValue]; !ok {
-				return errors.New("Unable to locate month name: " + matchValue)
-			}
-		} else if name == "DayName" {
-			if score, ok = DayLookup[lowerValue]; !ok {
-				return errors.New("Unable to locate day name : " + matchValue)
+		if translator, ok := translators[name]; ok {
+			if score, err = translator(matchValue); err != nil {
+				return err
 			}
 		} else if submap, ok := translation[name]; ok {
 			if score, ok = submap[matchValue]; !ok {
@@ -138,7 +247,9 @@ func (l *Logfile) PopulateMatchParts(subexpNames, matches []string, translation
 
 type Logfiles []*Logfile
 
-// Implement two of the sort.Interface methods needed
+// Len and Swap implement two of the sort.Interface methods needed by ByPriority.
+//
+// Deprecated: use SortLogfiles instead.
 func (l Logfiles) Len() int      { return len(l) }
 func (l Logfiles) Swap(i, j int) { l[i], l[j] = l[j], l[i] }
 
@@ -154,15 +265,24 @@ func (l Logfiles) IndexOf(s string) int {
 }
 
 // Provided the fileMatch regexp and translation map, populate all the Logfile
-// matchparts for use in sorting.
-func (l Logfiles) PopulateMatchParts(fileMatch *regexp.Regexp, translation SubmatchTranslationMap) error {
+// matchparts for use in sorting. If rules is non-empty, each logfile's
+// Priority is also populated via ApplyPriorityRules. translators is merged
+// over DefaultTranslators, so passing nil still resolves MonthName, DayName,
+// RFC3339, Unix, and Hex captures.
+func (l Logfiles) PopulateMatchParts(fileMatch *regexp.Regexp, translation SubmatchTranslationMap,
+	rules []PriorityRule, translators map[string]Translator) error {
+
 	errorlist := NewMultipleError()
 	subexpNames := fileMatch.SubexpNames()
+	effectiveTranslators := mergeTranslators(translators)
 	for _, logfile := range l {
 		matches := fileMatch.FindStringSubmatch(logfile.FileName)
-		if err := logfile.PopulateMatchParts(subexpNames, matches, translation); err != nil {
+		if err := logfile.PopulateMatchParts(subexpNames, matches, translation, effectiveTranslators); err != nil {
 			errorlist.AddMessage(err.Error())
 		}
+		if len(rules) > 0 {
+			logfile.ApplyPriorityRules(rules)
+		}
 	}
 	if errorlist.IsError() {
 		return errorlist
@@ -172,45 +292,239 @@ func (l Logfiles) PopulateMatchParts(fileMatch *regexp.Regexp, translation Subma
 
 // ByPriority implements the final method of the sort.Interface so that the embedded
 // LogfileMatches may be sorted by the priority of their matches parts
+//
+// Deprecated: use SortLogfiles instead, which is built on slices.SortStableFunc
+// and keeps the original order of equal entries.
 type ByPriority struct {
 	Logfiles
 	Priority []string
 }
 
-// Determine based on priority if which of the two is 'less' than the other
+// Less determines based on priority which of the two is 'less' than the other.
+//
+// Deprecated: use SortLogfiles instead.
 func (b ByPriority) Less(i, j int) bool {
-	var convert func(bool) bool
-	first := b.Logfiles[i]
-	second := b.Logfiles[j]
-	for _, part := range b.Priority {
-		convert = func(a bool) bool { return a }
-		if "^" == part[:1] {
+	return cmpLogfiles(b.Logfiles[i], b.Logfiles[j], b.Priority) < 0
+}
+
+// cmpLogfiles compares a and b according to priority, short-circuiting on the first
+// differing component. A leading "^" on a priority token means descending order,
+// which is applied here by negating the comparison's sign.
+func cmpLogfiles(a, b *Logfile, priority []string) int {
+	for _, part := range priority {
+		descending := strings.HasPrefix(part, "^")
+		if descending {
 			part = part[1:]
-			convert = func(a bool) bool { return !a }
 		}
-		if first.MatchParts[part] < second.MatchParts[part] {
-			return convert(true)
-		} else if first.MatchParts[part] > second.MatchParts[part] {
-			return convert(false)
+		var c int
+		switch part {
+		case MTimePriority:
+			c = a.ModTime.Compare(b.ModTime)
+		case RulePriority:
+			c = cmp.Compare(a.Priority, b.Priority)
+		default:
+			c = cmp.Compare(a.MatchParts[part], b.MatchParts[part])
+		}
+		if descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
 		}
 	}
-	// If we get here, it means all the parts are exactly equal, consider
-	// the first not less than the second
-	return false
+	// All parts are exactly equal.
+	return 0
 }
 
-// Scans a directory recursively filtering out files that match the fileMatch regexp
-func ScanDirectoryForLogfiles(directoryPath string, fileMatch *regexp.Regexp) Logfiles {
-	files := make(Logfiles, 0)
-	filepath.Walk(directoryPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
-			return nil
-		}
-		if fileMatch.MatchString(path) {
-			files = append(files, &Logfile{FileName: path})
+// SortLogfiles sorts files in place, oldest first, according to pattern.Priority.
+// It is the preferred entry point over the sort.Interface types above: it's built
+// on slices.SortStableFunc and shares cmpLogfiles with ByPriority.Less, which
+// short-circuits on the first differing priority component and handles the "^"
+// descending prefix by negating the comparison's sign.
+func SortLogfiles(files Logfiles, pattern SortPattern) error {
+	for _, part := range pattern.Priority {
+		if strings.TrimPrefix(part, "^") == "" {
+			return errors.New("logstream: empty priority token in SortPattern.Priority")
 		}
-		return nil
+	}
+	slices.SortStableFunc(files, func(a, b *Logfile) int {
+		return cmpLogfiles(a, b, pattern.Priority)
 	})
+	return nil
+}
+
+// Scanner walks a directory tree for logfiles matching FileMatch. Unlike a plain
+// filepath.Walk, it streams results as they're found, can be cancelled via the
+// context passed to Scan, reports walk errors instead of swallowing them, and
+// bounds recursion and concurrency for deeply nested or symlink-looped log roots.
+type Scanner struct {
+	Directory string
+	FileMatch *regexp.Regexp
+	// If positive, files whose mtime is older than MTimeCutoff (relative to now)
+	// are skipped entirely, which is useful for large log directories where
+	// scanning and reading every rotated file on startup is wasteful.
+	MTimeCutoff time.Duration
+	// MaxDepth bounds recursion below Directory. 0 means unlimited.
+	MaxDepth int
+	// FollowSymlinks causes symlinked directories to be descended into. Off by
+	// default to avoid symlink loops. Symlinked regular files (e.g. a
+	// current.log -> app.2024-01-01.log rotation symlink) are always emitted
+	// regardless of this setting.
+	FollowSymlinks bool
+	// ExcludePatterns prunes any path (file or directory) matching one of these
+	// from the scan.
+	ExcludePatterns []*regexp.Regexp
+	// Workers bounds the concurrency of the stat/regex-match phase. Defaults to 1.
+	Workers int
+}
+
+// Scan walks Directory and returns a channel of matched Logfiles and a channel
+// that receives at most one error summarizing every walk and stat failure
+// encountered. Both channels are closed once the scan completes or ctx is
+// cancelled.
+func (s *Scanner) Scan(ctx context.Context) (<-chan *Logfile, <-chan error) {
+	out := make(chan *Logfile)
+	errc := make(chan error, 1)
+
+	workers := s.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	var cutoffTime time.Time
+	if s.MTimeCutoff > 0 {
+		cutoffTime = time.Now().Add(-s.MTimeCutoff)
+	}
+
+	errlist := NewMultipleError()
+	var errmu sync.Mutex
+	addErr := func(msg string) {
+		errmu.Lock()
+		errlist.AddMessage(msg)
+		errmu.Unlock()
+	}
+
+	// wg covers the walker as well as the workers, so errlist is only read once
+	// nothing can add to it any more, including after ctx is cancelled.
+	var wg sync.WaitGroup
+	wg.Add(workers + 1)
+
+	paths := make(chan string)
+	go func() {
+		defer wg.Done()
+		defer close(paths)
+		s.walk(ctx, paths, addErr)
+	}()
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				info, err := os.Stat(path)
+				if err != nil {
+					addErr(err.Error())
+					continue
+				}
+				if !cutoffTime.IsZero() && info.ModTime().Before(cutoffTime) {
+					continue
+				}
+				if !s.FileMatch.MatchString(path) {
+					continue
+				}
+				select {
+				case out <- &Logfile{FileName: path, ModTime: info.ModTime()}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+		if errlist.IsError() {
+			errc <- errlist
+		}
+		close(errc)
+	}()
+
+	return out, errc
+}
+
+// walk descends Directory depth-first, sending every regular file's path
+// (subject to ExcludePatterns) to paths. Entries that match ExcludePatterns, or
+// directories beyond MaxDepth, are pruned. Errors from ReadDir and from
+// resolving symlinks are reported via addErr rather than silently discarded.
+func (s *Scanner) walk(ctx context.Context, paths chan<- string, addErr func(string)) {
+	var visit func(dir string, depth int)
+	visit = func(dir string, depth int) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			addErr(err.Error())
+			return
+		}
+		for _, entry := range entries {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			path := filepath.Join(dir, entry.Name())
+			if s.excluded(path) {
+				continue
+			}
+			isDir := entry.IsDir()
+			if entry.Type()&os.ModeSymlink != 0 {
+				resolved, err := os.Stat(path)
+				if err != nil {
+					addErr(err.Error())
+					continue
+				}
+				isDir = resolved.IsDir()
+				if isDir && !s.FollowSymlinks {
+					continue
+				}
+			}
+			if isDir {
+				if s.MaxDepth > 0 && depth >= s.MaxDepth {
+					continue
+				}
+				visit(path, depth+1)
+				continue
+			}
+			select {
+			case paths <- path:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	visit(s.Directory, 1)
+}
+
+func (s *Scanner) excluded(path string) bool {
+	for _, pattern := range s.ExcludePatterns {
+		if pattern.MatchString(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanDirectoryForLogfiles scans a directory recursively, filtering out files
+// that match the fileMatch regexp.
+//
+// Deprecated: use Scanner.Scan instead, which streams results as they're found,
+// can be cancelled, reports walk errors, and supports MaxDepth, FollowSymlinks,
+// ExcludePatterns, and concurrent Workers.
+func ScanDirectoryForLogfiles(directoryPath string, fileMatch *regexp.Regexp, mtimeCutoff time.Duration) Logfiles {
+	scanner := &Scanner{Directory: directoryPath, FileMatch: fileMatch, MTimeCutoff: mtimeCutoff}
+	out, errc := scanner.Scan(context.Background())
+	files := make(Logfiles, 0)
+	for logfile := range out {
+		files = append(files, logfile)
+	}
+	<-errc
 	return files
 }
 
@@ -275,24 +589,41 @@ type SubmatchTranslationMap map[string]MatchTranslationMap
 // higher the year, month, day indicates how close it is to *current*.
 type SortPattern struct {
 	// Regular expression for the files to match and parts of the filename to use for
-	// sorting. All parts to be sorted on must be captured and named. Special handling is
-	// provided for parts with names: MonthName, DayName.
-	// These names will be translated from short/long month/day names to the appropriate
-	// integer value.
+	// sorting. All parts to be sorted on must be captured and named. Capture names
+	// with a registered Translator (DefaultTranslators ships MonthName, DayName,
+	// RFC3339, Unix, and Hex) are translated via that func; anything else falls back
+	// to Translation, then to a plain integer parse.
 	FileMatch string
 	// Translation is used for custom ordering lookups where a custom value needs to be
 	// translated to a value for sorting. ie. a different tool using weekdays with values
 	// causing the wrong day of the week to be parsed first
 	Translation SubmatchTranslationMap
+	// Translators registers a func(raw string) (int, error) per capture name, for
+	// rotation schemes DefaultTranslators and Translation can't express, e.g. quarter
+	// names ("Q1".."Q4"), Julian day, or a syslog priority. Entries here override
+	// DefaultTranslators of the same name.
+	Translators map[string]Translator
 	// Priority list which should be provided to determine the most important parts of
 	// the matches to sort on. Most important captured name should be first. These will
 	// be sorted in ascending order representing *oldest first*. If this portions value
 	// increasing means its *older*, then it should be sorted in descending order by
 	// adding a ^ to the beginning.
+	//
+	// The reserved token MTimePriority ("MTime") may appear in place of a captured
+	// name to sort on the Logfile's filesystem ModTime instead, for rotation schemes
+	// where the filename doesn't encode a reliable date. Similarly the reserved
+	// token RulePriority ("Priority") sorts on the summed score produced by
+	// PriorityRules.
 	Priority []string
 	// Differentiators are used on portions of the file match to indicate unique
 	// non-changing portions that combined will yield an identifier for this 'logfile'
 	// If the name is not a subregex name, its raw value will be used to identify
 	// the log stream.
 	Differentiator []string
+	// PriorityRules independently boosts or deprioritizes logfiles whose FileName
+	// matches a given regexp, regardless of the named FileMatch captures. For example,
+	// always draining files under /var/log/critical/.* first and deprioritizing
+	// .*/debug/.* can be expressed as rules rather than shoehorned into a filename
+	// capture group. Include RulePriority in Priority for these scores to take effect.
+	PriorityRules []PriorityRule
 }
@@ -0,0 +1,236 @@
+package logstream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"testing"
+	"time"
+)
+
+func mustWriteFile(t *testing.T, path, contents string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("MkdirAll(%s): %v", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatalf("WriteFile(%s): %v", path, err)
+	}
+}
+
+// collectScan drains a Scanner's output and error channels, failing the test
+// if it takes longer than a few seconds (a hang would indicate a deadlocked
+// pipeline).
+func collectScan(t *testing.T, s *Scanner, ctx context.Context) ([]string, error) {
+	t.Helper()
+	out, errc := s.Scan(ctx)
+	var names []string
+	timeout := time.After(5 * time.Second)
+	for out != nil || errc != nil {
+		select {
+		case logfile, ok := <-out:
+			if !ok {
+				out = nil
+				continue
+			}
+			names = append(names, logfile.FileName)
+		case err, ok := <-errc:
+			if !ok {
+				errc = nil
+				continue
+			}
+			return names, err
+		case <-timeout:
+			t.Fatal("Scan did not complete in time")
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func TestScannerMaxDepth(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "top.log"), "")
+	mustWriteFile(t, filepath.Join(root, "a", "mid.log"), "")
+	mustWriteFile(t, filepath.Join(root, "a", "b", "deep.log"), "")
+
+	fileMatch := regexp.MustCompile(`\.log$`)
+
+	cases := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{maxDepth: 1, want: []string{filepath.Join(root, "top.log")}},
+		{maxDepth: 2, want: []string{filepath.Join(root, "a", "mid.log"), filepath.Join(root, "top.log")}},
+		{maxDepth: 0, want: []string{
+			filepath.Join(root, "a", "b", "deep.log"),
+			filepath.Join(root, "a", "mid.log"),
+			filepath.Join(root, "top.log"),
+		}},
+	}
+
+	for _, c := range cases {
+		s := &Scanner{Directory: root, FileMatch: fileMatch, MaxDepth: c.maxDepth}
+		got, err := collectScan(t, s, context.Background())
+		if err != nil {
+			t.Fatalf("MaxDepth=%d: unexpected error: %v", c.maxDepth, err)
+		}
+		sort.Strings(c.want)
+		if !equalStrings(got, c.want) {
+			t.Errorf("MaxDepth=%d: got %v, want %v", c.maxDepth, got, c.want)
+		}
+	}
+}
+
+func TestScannerExcludePatterns(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "keep.log"), "")
+	mustWriteFile(t, filepath.Join(root, "skip.log"), "")
+	mustWriteFile(t, filepath.Join(root, "secret", "inside.log"), "")
+
+	s := &Scanner{
+		Directory: root,
+		FileMatch: regexp.MustCompile(`\.log$`),
+		ExcludePatterns: []*regexp.Regexp{
+			regexp.MustCompile(`skip\.log$`),
+			regexp.MustCompile(`secret$`),
+		},
+	}
+	got, err := collectScan(t, s, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(root, "keep.log")}
+	if !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestScannerFollowSymlinks(t *testing.T) {
+	root := t.TempDir()
+	mustWriteFile(t, filepath.Join(root, "real", "file.log"), "")
+	if err := os.Symlink(filepath.Join(root, "real"), filepath.Join(root, "link")); err != nil {
+		t.Skipf("symlinks unsupported in this environment: %v", err)
+	}
+
+	fileMatch := regexp.MustCompile(`\.log$`)
+
+	s := &Scanner{Directory: root, FileMatch: fileMatch, FollowSymlinks: false}
+	got, err := collectScan(t, s, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{filepath.Join(root, "real", "file.log")}
+	if !equalStrings(got, want) {
+		t.Errorf("FollowSymlinks=false: got %v, want %v", got, want)
+	}
+
+	s = &Scanner{Directory: root, FileMatch: fileMatch, FollowSymlinks: true}
+	got, err = collectScan(t, s, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want = []string{filepath.Join(root, "link", "file.log"), filepath.Join(root, "real", "file.log")}
+	if !equalStrings(got, want) {
+		t.Errorf("FollowSymlinks=true: got %v, want %v", got, want)
+	}
+}
+
+// TestScannerSymlinkedFileAlwaysEmitted verifies that a symlinked *regular
+// file* (e.g. a current.log -> app.2024-01-01.log rotation symlink) is always
+// emitted, since FollowSymlinks only governs descending into symlinked
+// directories.
+func TestScannerSymlinkedFileAlwaysEmitted(t *testing.T) {
+	root := t.TempDir()
+	target := filepath.Join(root, "app.2024-01-01.log")
+	mustWriteFile(t, target, "")
+	link := filepath.Join(root, "current.log")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks unsupported in this environment: %v", err)
+	}
+
+	fileMatch := regexp.MustCompile(`\.log$`)
+	want := []string{link, target}
+	sort.Strings(want)
+
+	s := &Scanner{Directory: root, FileMatch: fileMatch, FollowSymlinks: false}
+	got, err := collectScan(t, s, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("FollowSymlinks=false: got %v, want %v", got, want)
+	}
+
+	s = &Scanner{Directory: root, FileMatch: fileMatch, FollowSymlinks: true}
+	got, err = collectScan(t, s, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !equalStrings(got, want) {
+		t.Errorf("FollowSymlinks=true: got %v, want %v", got, want)
+	}
+}
+
+func TestScannerContextCancellation(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 50; i++ {
+		mustWriteFile(t, filepath.Join(root, "dir"+string(rune('a'+i%26)), "file.log"), "")
+	}
+
+	s := &Scanner{Directory: root, FileMatch: regexp.MustCompile(`\.log$`), Workers: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	out, errc := s.Scan(ctx)
+
+	// Take one result, then cancel; the scan must wind down instead of hanging.
+	select {
+	case <-out:
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not receive first result in time")
+	}
+	cancel()
+
+	timeout := time.After(5 * time.Second)
+	for out != nil || errc != nil {
+		select {
+		case _, ok := <-out:
+			if !ok {
+				out = nil
+			}
+		case _, ok := <-errc:
+			if !ok {
+				errc = nil
+			}
+		case <-timeout:
+			t.Fatal("Scan did not wind down after cancellation")
+		}
+	}
+}
+
+func TestScannerReportsWalkErrors(t *testing.T) {
+	root := t.TempDir()
+	// Point Directory at a regular file: os.ReadDir on it fails regardless of
+	// privileges, giving a deterministic walk error to assert on.
+	notADir := filepath.Join(root, "not-a-dir")
+	mustWriteFile(t, notADir, "")
+
+	s := &Scanner{Directory: notADir, FileMatch: regexp.MustCompile(`\.log$`)}
+	_, err := collectScan(t, s, context.Background())
+	if err == nil {
+		t.Fatal("expected an error from scanning a non-directory, got nil")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,206 @@
+package logstream
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestCmpLogfilesMTimePriority(t *testing.T) {
+	older := &Logfile{FileName: "older", ModTime: time.Unix(100, 0)}
+	newer := &Logfile{FileName: "newer", ModTime: time.Unix(200, 0)}
+
+	if c := cmpLogfiles(older, newer, []string{MTimePriority}); c >= 0 {
+		t.Errorf("ascending MTime: cmpLogfiles(older, newer) = %d, want negative", c)
+	}
+	if c := cmpLogfiles(newer, older, []string{MTimePriority}); c <= 0 {
+		t.Errorf("ascending MTime: cmpLogfiles(newer, older) = %d, want positive", c)
+	}
+	if c := cmpLogfiles(older, newer, []string{"^" + MTimePriority}); c <= 0 {
+		t.Errorf("descending MTime: cmpLogfiles(older, newer) = %d, want positive", c)
+	}
+	if c := cmpLogfiles(older, older, []string{MTimePriority}); c != 0 {
+		t.Errorf("equal MTime: cmpLogfiles(older, older) = %d, want 0", c)
+	}
+}
+
+func TestScannerMTimeCutoff(t *testing.T) {
+	root := t.TempDir()
+	oldPath := filepath.Join(root, "old.log")
+	newPath := filepath.Join(root, "new.log")
+	mustWriteFile(t, oldPath, "")
+	mustWriteFile(t, newPath, "")
+
+	oldTime := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(oldPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	s := &Scanner{
+		Directory:   root,
+		FileMatch:   regexp.MustCompile(`\.log$`),
+		MTimeCutoff: time.Hour,
+	}
+	got, err := collectScan(t, s, context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{newPath}
+	if !equalStrings(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestApplyPriorityRules(t *testing.T) {
+	rules := []PriorityRule{
+		{Pattern: regexp.MustCompile(`^/var/log/critical/`), Priority: 10},
+		{Pattern: regexp.MustCompile(`/debug/`), Priority: -5},
+	}
+
+	cases := []struct {
+		fileName string
+		want     int
+	}{
+		{fileName: "/var/log/critical/a.log", want: 10},
+		{fileName: "/var/log/normal/debug/a.log", want: -5},
+		{fileName: "/var/log/critical/debug/a.log", want: 5},
+		{fileName: "/var/log/normal/a.log", want: 0},
+	}
+	for _, c := range cases {
+		l := &Logfile{FileName: c.fileName}
+		l.ApplyPriorityRules(rules)
+		if l.Priority != c.want {
+			t.Errorf("ApplyPriorityRules(%q) = %d, want %d", c.fileName, l.Priority, c.want)
+		}
+	}
+}
+
+func TestCmpLogfilesRulePriority(t *testing.T) {
+	low := &Logfile{FileName: "low", Priority: 1}
+	high := &Logfile{FileName: "high", Priority: 10}
+
+	if c := cmpLogfiles(low, high, []string{RulePriority}); c >= 0 {
+		t.Errorf("ascending Priority: cmpLogfiles(low, high) = %d, want negative", c)
+	}
+	if c := cmpLogfiles(low, high, []string{"^" + RulePriority}); c <= 0 {
+		t.Errorf("descending Priority: cmpLogfiles(low, high) = %d, want positive", c)
+	}
+}
+
+func TestPopulateMatchPartsAppliesPriorityRules(t *testing.T) {
+	files := Logfiles{
+		{FileName: "/var/log/critical/a.log"},
+		{FileName: "/var/log/debug/b.log"},
+	}
+	fileMatch := regexp.MustCompile(`a\.log|b\.log`)
+	rules := []PriorityRule{
+		{Pattern: regexp.MustCompile(`critical`), Priority: 10},
+		{Pattern: regexp.MustCompile(`debug`), Priority: -5},
+	}
+	if err := files.PopulateMatchParts(fileMatch, nil, rules, nil); err != nil {
+		t.Fatalf("PopulateMatchParts: %v", err)
+	}
+	if files[0].Priority != 10 {
+		t.Errorf("files[0].Priority = %d, want 10", files[0].Priority)
+	}
+	if files[1].Priority != -5 {
+		t.Errorf("files[1].Priority = %d, want -5", files[1].Priority)
+	}
+}
+
+func TestSortLogfiles(t *testing.T) {
+	newLogfile := func(name string, year, seq int) *Logfile {
+		return &Logfile{
+			FileName:   name,
+			MatchParts: map[string]int{"Year": year, "Seq": seq},
+		}
+	}
+
+	cases := []struct {
+		name     string
+		files    Logfiles
+		priority []string
+		want     []string
+	}{
+		{
+			name: "ascending single part",
+			files: Logfiles{
+				newLogfile("b", 2012, 0),
+				newLogfile("a", 2011, 0),
+				newLogfile("c", 2013, 0),
+			},
+			priority: []string{"Year"},
+			want:     []string{"a", "b", "c"},
+		},
+		{
+			name: "descending single part",
+			files: Logfiles{
+				newLogfile("a", 2011, 0),
+				newLogfile("c", 2013, 0),
+				newLogfile("b", 2012, 0),
+			},
+			priority: []string{"^Year"},
+			want:     []string{"c", "b", "a"},
+		},
+		{
+			name: "mixed priority list short-circuits on first differing part",
+			files: Logfiles{
+				newLogfile("same-year-high-seq", 2012, 5),
+				newLogfile("earlier-year", 2011, 99),
+				newLogfile("same-year-low-seq", 2012, 1),
+			},
+			priority: []string{"Year", "^Seq"},
+			want:     []string{"earlier-year", "same-year-high-seq", "same-year-low-seq"},
+		},
+		{
+			name: "ties preserve original order (stable sort)",
+			files: Logfiles{
+				newLogfile("first", 2012, 0),
+				newLogfile("second", 2012, 0),
+				newLogfile("third", 2012, 0),
+			},
+			priority: []string{"Year"},
+			want:     []string{"first", "second", "third"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			pattern := SortPattern{Priority: c.priority}
+			if err := SortLogfiles(c.files, pattern); err != nil {
+				t.Fatalf("SortLogfiles: %v", err)
+			}
+			var got []string
+			for _, f := range c.files {
+				got = append(got, f.FileName)
+			}
+			if !equalStrings(got, c.want) {
+				t.Errorf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSortLogfilesRejectsEmptyPriorityToken(t *testing.T) {
+	files := Logfiles{{FileName: "a"}}
+	pattern := SortPattern{Priority: []string{"^"}}
+	if err := SortLogfiles(files, pattern); err == nil {
+		t.Error("expected an error for an empty priority token, got nil")
+	}
+}
+
+func TestByPriorityLessMatchesCmpLogfiles(t *testing.T) {
+	a := &Logfile{FileName: "a", MatchParts: map[string]int{"Seq": 1}}
+	b := &Logfile{FileName: "b", MatchParts: map[string]int{"Seq": 2}}
+	bp := ByPriority{Logfiles: Logfiles{a, b}, Priority: []string{"Seq"}}
+
+	if !bp.Less(0, 1) {
+		t.Error("ByPriority.Less(0, 1) = false, want true")
+	}
+	if bp.Less(1, 0) {
+		t.Error("ByPriority.Less(1, 0) = true, want false")
+	}
+}
@@ -0,0 +1,124 @@
+package logstream
+
+import "testing"
+
+func TestBuiltinTranslators(t *testing.T) {
+	cases := []struct {
+		name      string
+		raw       string
+		want      int
+		wantError bool
+	}{
+		{name: "MonthName", raw: "March", want: 3},
+		{name: "MonthName", raw: "nope", wantError: true},
+		{name: "DayName", raw: "Fri", want: 4},
+		{name: "DayName", raw: "nope", wantError: true},
+		{name: "RFC3339", raw: "2024-01-02T03:04:05Z", want: 1704164645},
+		{name: "RFC3339", raw: "not-a-timestamp", wantError: true},
+		{name: "Unix", raw: "1704164645", want: 1704164645},
+		{name: "Unix", raw: "not-a-number", wantError: true},
+		{name: "Hex", raw: "2a", want: 42},
+		{name: "Hex", raw: "not-hex", wantError: true},
+	}
+	for _, c := range cases {
+		translator, ok := DefaultTranslators[c.name]
+		if !ok {
+			t.Fatalf("DefaultTranslators missing %q", c.name)
+		}
+		got, err := translator(c.raw)
+		if c.wantError {
+			if err == nil {
+				t.Errorf("%s(%q): expected an error, got score %d", c.name, c.raw, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s(%q): unexpected error: %v", c.name, c.raw, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s(%q) = %d, want %d", c.name, c.raw, got, c.want)
+		}
+	}
+}
+
+func TestMergeTranslatorsOverridesDefaults(t *testing.T) {
+	custom := map[string]Translator{
+		"MonthName": func(raw string) (int, error) { return 99, nil },
+		"Quarter":   func(raw string) (int, error) { return 1, nil },
+	}
+	merged := mergeTranslators(custom)
+
+	score, err := merged["MonthName"]("march")
+	if err != nil || score != 99 {
+		t.Errorf("merged[MonthName](\"march\") = (%d, %v), want (99, nil)", score, err)
+	}
+	if _, ok := merged["DayName"]; !ok {
+		t.Error("merged should still contain the unshadowed DefaultTranslators entry DayName")
+	}
+	if _, ok := merged["Quarter"]; !ok {
+		t.Error("merged should contain the custom-only entry Quarter")
+	}
+}
+
+func TestMergeTranslatorsNilCustom(t *testing.T) {
+	merged := mergeTranslators(nil)
+	if len(merged) != len(DefaultTranslators) {
+		t.Errorf("mergeTranslators(nil) has %d entries, want %d", len(merged), len(DefaultTranslators))
+	}
+}
+
+func TestPopulateMatchPartsTranslatorPrecedence(t *testing.T) {
+	// A capture named "Hex" has a registered Translator (DefaultTranslators),
+	// so it must be parsed as hex even though it's also all-digits and would
+	// otherwise fall through to the plain-digit path.
+	l := &Logfile{}
+	err := l.PopulateMatchParts(
+		[]string{"", "Hex"},
+		[]string{"", "042"},
+		nil,
+		DefaultTranslators,
+	)
+	if err != nil {
+		t.Fatalf("PopulateMatchParts: %v", err)
+	}
+	if l.MatchParts["Hex"] != 0x42 {
+		t.Errorf("MatchParts[Hex] = %d, want %d", l.MatchParts["Hex"], 0x42)
+	}
+}
+
+func TestPopulateMatchPartsFallsBackToTranslationThenDigit(t *testing.T) {
+	translation := SubmatchTranslationMap{
+		"Weekday": MatchTranslationMap{"mon": 10},
+	}
+	l := &Logfile{}
+	err := l.PopulateMatchParts(
+		[]string{"", "Weekday", "Seq"},
+		[]string{"", "mon", "007"},
+		translation,
+		DefaultTranslators,
+	)
+	if err != nil {
+		t.Fatalf("PopulateMatchParts: %v", err)
+	}
+	if l.MatchParts["Weekday"] != 10 {
+		t.Errorf("MatchParts[Weekday] = %d, want 10 (from Translation, no registered Translator)", l.MatchParts["Weekday"])
+	}
+	if l.MatchParts["Seq"] != 7 {
+		t.Errorf("MatchParts[Seq] = %d, want 7 (plain digit fallback)", l.MatchParts["Seq"])
+	}
+}
+
+func TestPopulateMatchPartsCustomTranslatorOverridesDefault(t *testing.T) {
+	custom := mergeTranslators(map[string]Translator{
+		"Hex": func(raw string) (int, error) { return -1, nil },
+	})
+	l := &Logfile{}
+	err := l.PopulateMatchParts([]string{"", "Hex"}, []string{"", "2a"}, nil, custom)
+	if err != nil {
+		t.Fatalf("PopulateMatchParts: %v", err)
+	}
+	if l.MatchParts["Hex"] != -1 {
+		t.Errorf("MatchParts[Hex] = %d, want -1 (custom Translators entry should win)", l.MatchParts["Hex"])
+	}
+}